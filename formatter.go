@@ -0,0 +1,76 @@
+package logredis
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Formatter turns a logrus.Entry into the bytes written to Redis.
+type Formatter interface {
+	Format(entry *logrus.Entry) ([]byte, error)
+}
+
+// LogstashV0Formatter formats entries using the legacy Logstash v0 schema.
+type LogstashV0Formatter struct{}
+
+// Format implements Formatter.
+func (LogstashV0Formatter) Format(entry *logrus.Entry) ([]byte, error) {
+	js, err := json.Marshal(createV0Message(entry))
+	if err != nil {
+		return nil, fmt.Errorf("error creating message for REDIS: %s", err)
+	}
+	return js, nil
+}
+
+// LogstashV1Formatter formats entries using the Logstash v1 schema.
+type LogstashV1Formatter struct{}
+
+// Format implements Formatter.
+func (LogstashV1Formatter) Format(entry *logrus.Entry) ([]byte, error) {
+	js, err := json.Marshal(createV1Message(entry))
+	if err != nil {
+		return nil, fmt.Errorf("error creating message for REDIS: %s", err)
+	}
+	return js, nil
+}
+
+// ECSFormatter formats entries using a subset of the Elastic Common Schema
+// (https://www.elastic.co/guide/en/ecs/current/index.html), for shipping
+// directly into Elasticsearch ingest pipelines. Unlike LogstashV0Formatter
+// and LogstashV1Formatter, entry fields are preserved as their native JSON
+// types instead of being coerced to strings.
+type ECSFormatter struct{}
+
+// Format implements Formatter.
+func (ECSFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	doc := make(map[string]interface{}, len(entry.Data)+3)
+	for key, value := range entry.Data {
+		doc[key] = value
+	}
+
+	doc["@timestamp"] = entry.Time.UTC().Format(time.RFC3339Nano)
+	doc["message"] = entry.Message
+	doc["log"] = map[string]string{"level": entry.Level.String()}
+	doc["host"] = map[string]string{"name": reportHostname()}
+
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error creating message for REDIS: %s", err)
+	}
+	return js, nil
+}
+
+// RawFormatter is a passthrough that reuses logrus's own JSONFormatter, so
+// entries are shipped to Redis in exactly the shape logrus would otherwise
+// write to its output.
+type RawFormatter struct {
+	JSONFormatter logrus.JSONFormatter
+}
+
+// Format implements Formatter.
+func (f *RawFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	return f.JSONFormatter.Format(entry)
+}
@@ -1,26 +1,176 @@
 package logredis
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/tls"
 	"fmt"
+	"math/rand"
 	"os"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
-	"github.com/garyburd/redigo/redis"
 )
 
+// Mode selects the Redis topology NewHookWithOptions connects to.
+type Mode int
+
+const (
+	// ModeSingle connects to a single standalone Redis instance. This is the default.
+	ModeSingle Mode = iota
+	// ModeSentinel connects through Redis Sentinel for HA failover.
+	ModeSentinel
+	// ModeCluster connects to a sharded Redis Cluster deployment.
+	ModeCluster
+)
+
+// Options configures how the hook connects to Redis. Zero value connects to
+// a single instance at Host:Port.
+type Options struct {
+	// Mode selects single-host, Sentinel, or Cluster connectivity.
+	Mode Mode
+
+	// Host and Port are used when Mode is ModeSingle.
+	Host string
+	Port int
+
+	// Addrs lists "host:port" Sentinel or Cluster node addresses and is
+	// used when Mode is ModeSentinel or ModeCluster.
+	Addrs []string
+
+	// MasterName is the Sentinel master name, required when Mode is ModeSentinel.
+	MasterName string
+
+	// Username and Password authenticate the connection. Username requires
+	// Redis 6 ACL support and is ignored if Password is empty.
+	Username string
+	Password string
+
+	// DB selects the logical database via SELECT. It is ignored in
+	// ModeCluster, which does not support multiple databases.
+	DB int
+
+	// TLSConfig enables TLS when non-nil.
+	TLSConfig *tls.Config
+
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolSize     int
+
+	// BatchSize is the maximum number of entries pipelined into a single
+	// RPUSH in async mode. Defaults to 1 (no batching) if unset.
+	BatchSize int
+
+	// FlushInterval bounds how long a partial batch waits before being
+	// flushed. Defaults to one second if unset.
+	FlushInterval time.Duration
+
+	// BlockOnFull makes Fire block until the async queue has room instead
+	// of discarding the entry when it's full.
+	BlockOnFull bool
+
+	// Formatter controls how entries are serialized before being sent to
+	// Redis. If nil, it's chosen from the format string passed to
+	// NewHookWithOptions ("v0", "v1", "ecs", or "raw"). DeliveryStream does
+	// not use it: each field is written as its own stream field.
+	Formatter Formatter
+
+	// DeliveryMode selects how entries reach Redis. Defaults to DeliveryList.
+	DeliveryMode DeliveryMode
+
+	// MaxLen caps a stream's length when DeliveryMode is DeliveryStream. Zero
+	// means unbounded.
+	MaxLen int64
+
+	// Approximate trims streams with the "~" flag (MAXLEN ~ N) instead of an
+	// exact trim, which is cheaper for Redis to apply. Only used when
+	// DeliveryMode is DeliveryStream.
+	Approximate bool
+
+	// MaxRetries is the number of additional attempts made after a failed
+	// delivery, with exponential backoff and jitter between attempts. Zero
+	// disables retries.
+	MaxRetries int
+
+	// RetryBaseDelay is the delay before the first retry; it roughly
+	// doubles on each subsequent attempt. Defaults to 100ms if unset.
+	RetryBaseDelay time.Duration
+
+	// OnError, if set, is called whenever an entry could not be formatted,
+	// or could not be delivered to Redis after all retries were exhausted.
+	// It runs synchronously on the goroutine delivering the entry (the
+	// hook's processing goroutine in async mode, the caller of Fire
+	// otherwise), so it must not block.
+	OnError func(err error, entry *logrus.Entry)
+
+	// SpilloverPath, if set, appends undeliverable entries as JSONL to this
+	// file once SpilloverThreshold consecutive delivery failures have been
+	// observed, so logs survive a prolonged Redis outage instead of being
+	// dropped.
+	SpilloverPath string
+
+	// SpilloverThreshold is the number of consecutive delivery failures
+	// before spillover kicks in. Defaults to 1 if unset.
+	SpilloverThreshold int
+}
+
+// DeliveryMode selects how entries are delivered to Redis.
+type DeliveryMode int
+
+const (
+	// DeliveryList RPUSHes each entry onto a list. This is the default.
+	DeliveryList DeliveryMode = iota
+	// DeliveryStream XADDs each entry onto a Redis Stream.
+	DeliveryStream
+	// DeliveryPubSub PUBLISHes each entry to subscribers, with no persistence.
+	DeliveryPubSub
+)
+
+// redisClient is the subset of the go-redis command set the hook needs.
+// *redis.Client, *redis.ClusterClient, and the Sentinel failover client
+// returned by redis.NewFailoverClient all satisfy it.
+type redisClient interface {
+	RPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd
+	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
+	Ping(ctx context.Context) *redis.StatusCmd
+	Close() error
+}
+
 // RedisHook to sends logs to Redis server
 type RedisHook struct {
-	RedisPool      *redis.Pool
+	RedisClient    redisClient
 	RedisHost      string
 	RedisKey       string
-	LogstashFormat string
 	RedisPort      int
 	Level          logrus.Level
 	Async          bool
 	EntryQueue     chan *logrus.Entry
 	Quit           chan int
+	BatchSize      int
+	FlushInterval  time.Duration
+	BlockOnFull    bool
+	Formatter      Formatter
+	DeliveryMode   DeliveryMode
+	MaxLen         int64
+	Approximate    bool
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	OnError        func(err error, entry *logrus.Entry)
+
+	SpilloverPath      string
+	SpilloverThreshold int
+
+	done                chan struct{}
+	quitOnce            sync.Once
+	closeOnce           sync.Once
+	closeErr            error
+	mu                  sync.Mutex
+	consecutiveFailures int
+	spilloverFile       *os.File
 }
 
 // LogstashMessageV0 represents v0 format
@@ -52,48 +202,168 @@ type LogstashMessageV1 struct {
 	CustomFields map[string]string `json:"@custom_fields"`
 }
 
-// NewHook creates a hook to be added to an instance of logger
+// NewHook creates a hook to be added to an instance of logger, connecting to
+// a single standalone Redis instance. For Sentinel or Cluster deployments,
+// use NewHookWithOptions.
 func NewHook(host string, port int, key string, format string, level logrus.Level, async bool, bufferSize int) (*RedisHook, error) {
-	pool := newRedisConnectionPool(host, port)
+	opts := Options{Mode: ModeSingle, Host: host, Port: port}
+	return NewHookWithOptions(opts, key, format, level, async, bufferSize)
+}
 
-	// test if connection with REDIS can be established
-	conn := pool.Get()
-	defer conn.Close()
+// NewHookWithOptions creates a hook to be added to an instance of logger.
+// opts.Mode selects whether the hook talks to a single instance, a Sentinel
+// deployment, or a Redis Cluster.
+func NewHookWithOptions(opts Options, key string, format string, level logrus.Level, async bool, bufferSize int) (*RedisHook, error) {
+	client, err := newRedisClient(opts)
+	if err != nil {
+		return nil, err
+	}
 
 	// check connection
-	_, err := conn.Do("PING")
-	if err != nil {
+	if err := client.Ping(context.Background()).Err(); err != nil {
 		return nil, fmt.Errorf("unable to connect to REDIS: %s", err)
 	}
 
-	// by default, use V0 format
-	if strings.ToLower(format) != "v0" && strings.ToLower(format) != "v1" {
-		format = "v0"
+	formatter := opts.Formatter
+	if formatter == nil {
+		formatter = formatterFromName(format)
 	}
 
-	redisHook := RedisHook {
-		RedisHost:      host,
-		RedisPool:      pool,
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	flushInterval := opts.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	retryBaseDelay := opts.RetryBaseDelay
+	if retryBaseDelay <= 0 {
+		retryBaseDelay = 100 * time.Millisecond
+	}
+
+	spilloverThreshold := opts.SpilloverThreshold
+	if spilloverThreshold <= 0 {
+		spilloverThreshold = 1
+	}
+
+	redisHook := RedisHook{
+		RedisHost:      opts.Host,
+		RedisPort:      opts.Port,
+		RedisClient:    client,
 		RedisKey:       key,
-		LogstashFormat: format,
+		Formatter:      formatter,
 		Level:          level,
 		Async:          async,
 		EntryQueue:     nil,
 		Quit:           nil,
+		BatchSize:      batchSize,
+		FlushInterval:  flushInterval,
+		BlockOnFull:    opts.BlockOnFull,
+		DeliveryMode:   opts.DeliveryMode,
+		MaxLen:         opts.MaxLen,
+		Approximate:    opts.Approximate,
+
+		MaxRetries:     opts.MaxRetries,
+		RetryBaseDelay: retryBaseDelay,
+		OnError:        opts.OnError,
+
+		SpilloverPath:      opts.SpilloverPath,
+		SpilloverThreshold: spilloverThreshold,
 	}
 
 	if async {
 		redisHook.EntryQueue = make(chan *logrus.Entry, bufferSize)
 		redisHook.Quit = make(chan int)
+		redisHook.done = make(chan struct{})
 		go redisHook.asyncProcessing()
 	}
 
 	return &redisHook, nil
 }
 
+// formatterFromName resolves one of the built-in formatters by name,
+// defaulting to LogstashV0Formatter for an empty or unrecognized name.
+func formatterFromName(name string) Formatter {
+	switch strings.ToLower(name) {
+	case "v1":
+		return LogstashV1Formatter{}
+	case "ecs":
+		return ECSFormatter{}
+	case "raw":
+		return &RawFormatter{}
+	default:
+		return LogstashV0Formatter{}
+	}
+}
+
+func newRedisClient(opts Options) (redisClient, error) {
+	switch opts.Mode {
+	case ModeSentinel:
+		if opts.MasterName == "" {
+			return nil, fmt.Errorf("logredis: MasterName is required in sentinel mode")
+		}
+		if len(opts.Addrs) == 0 {
+			return nil, fmt.Errorf("logredis: Addrs is required in sentinel mode")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    opts.MasterName,
+			SentinelAddrs: opts.Addrs,
+			Username:      opts.Username,
+			Password:      opts.Password,
+			DB:            opts.DB,
+			TLSConfig:     opts.TLSConfig,
+			DialTimeout:   opts.DialTimeout,
+			ReadTimeout:   opts.ReadTimeout,
+			WriteTimeout:  opts.WriteTimeout,
+			PoolSize:      opts.PoolSize,
+		}), nil
+	case ModeCluster:
+		if len(opts.Addrs) == 0 {
+			return nil, fmt.Errorf("logredis: Addrs is required in cluster mode")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        opts.Addrs,
+			Username:     opts.Username,
+			Password:     opts.Password,
+			TLSConfig:    opts.TLSConfig,
+			DialTimeout:  opts.DialTimeout,
+			ReadTimeout:  opts.ReadTimeout,
+			WriteTimeout: opts.WriteTimeout,
+			PoolSize:     opts.PoolSize,
+		}), nil
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:         fmt.Sprintf("%s:%d", opts.Host, opts.Port),
+			Username:     opts.Username,
+			Password:     opts.Password,
+			DB:           opts.DB,
+			TLSConfig:    opts.TLSConfig,
+			DialTimeout:  opts.DialTimeout,
+			ReadTimeout:  opts.ReadTimeout,
+			WriteTimeout: opts.WriteTimeout,
+			PoolSize:     opts.PoolSize,
+		}), nil
+	}
+}
+
 // Fire is called when a log event is fired.
 func (hook *RedisHook) Fire(entry *logrus.Entry) error {
 	if hook.Async {
+		if hook.BlockOnFull {
+			// Also select on Quit so a producer blocked on a full queue is
+			// released once Close starts shutting the hook down, instead
+			// of leaking forever with nothing left to drain EntryQueue.
+			select {
+			case hook.EntryQueue <- entry:
+			case <-hook.Quit:
+				fmt.Println("Redis hook is shutting down, log entry discarded")
+			}
+			return nil
+		}
+
 		select {
 		case hook.EntryQueue <- entry:
 		default:
@@ -106,6 +376,37 @@ func (hook *RedisHook) Fire(entry *logrus.Entry) error {
 	}
 }
 
+// Close flushes any entries still queued in async mode, waiting up to ctx's
+// deadline, then releases the Redis client and spillover file. The hook
+// must not be used again after Close returns successfully.
+//
+// Close is safe to call more than once, including after a prior call timed
+// out: cleanup only latches in once it actually completes, so a caller
+// whose first Close(ctx) hit its deadline can retry with a longer context
+// and still get the Redis client and spillover file released.
+func (hook *RedisHook) Close(ctx context.Context) error {
+	if hook.Async {
+		hook.quitOnce.Do(func() { close(hook.Quit) })
+		select {
+		case <-hook.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	hook.closeOnce.Do(func() {
+		hook.mu.Lock()
+		if hook.spilloverFile != nil {
+			hook.spilloverFile.Close()
+		}
+		hook.mu.Unlock()
+
+		hook.closeErr = hook.RedisClient.Close()
+	})
+
+	return hook.closeErr
+}
+
 // Levels returns the available logging levels.
 func (hook *RedisHook) Levels() []logrus.Level {
 	levels := make([]logrus.Level, 1)
@@ -133,40 +434,241 @@ func (hook *RedisHook) Levels() []logrus.Level {
 	return levels
 }
 
+// asyncProcessing drains the EntryQueue in batches of up to BatchSize,
+// flushing whenever the batch fills or FlushInterval elapses, and hands each
+// batch to processBatch, which pipelines it into a single RPUSH in
+// DeliveryList mode.
 func (hook *RedisHook) asyncProcessing() {
+	defer close(hook.done)
+
+	batch := make([]*logrus.Entry, 0, hook.BatchSize)
+	ticker := time.NewTicker(hook.FlushInterval)
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		hook.processBatch(batch)
+		batch = batch[:0]
+	}
+
 	for {
 		select {
-		case entry := <- hook.EntryQueue:
-			hook.processEntry(entry)
-		case <- hook.Quit:
-			return
+		case entry := <-hook.EntryQueue:
+			batch = append(batch, entry)
+			if len(batch) >= hook.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-hook.Quit:
+			// Drain whatever is still buffered in EntryQueue before the
+			// final flush so Close doesn't drop entries Fire already
+			// accepted.
+			for {
+				select {
+				case entry := <-hook.EntryQueue:
+					batch = append(batch, entry)
+				default:
+					flush()
+					return
+				}
+			}
 		}
 	}
 }
 
 func (hook *RedisHook) processEntry(entry *logrus.Entry) error {
-	var msg interface{}
+	switch hook.DeliveryMode {
+	case DeliveryStream:
+		return hook.deliverStream(entry)
+	case DeliveryPubSub:
+		return hook.deliverPubSub(entry)
+	default:
+		return hook.deliverList(entry)
+	}
+}
 
-	switch hook.LogstashFormat {
-	case "v0":
-		msg = createV0Message(entry)
-	case "v1":
-		msg = createV1Message(entry)
+// processBatch delivers a batch drained by asyncProcessing. Only
+// DeliveryList pipelines the batch into a single round-trip; XADD and
+// PUBLISH have no multi-key equivalent, so entries are delivered one by one.
+func (hook *RedisHook) processBatch(entries []*logrus.Entry) error {
+	if hook.DeliveryMode != DeliveryList {
+		for _, entry := range entries {
+			hook.processEntry(entry)
+		}
+		return nil
+	}
+
+	values := make([]interface{}, 0, len(entries))
+	formatted := make([]*logrus.Entry, 0, len(entries))
+	for _, entry := range entries {
+		js, err := hook.formatEntry(entry)
+		if err != nil {
+			continue
+		}
+		values = append(values, js)
+		formatted = append(formatted, entry)
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	return hook.sendBatchWithRetry(formatted, func() error {
+		return hook.RedisClient.RPush(context.Background(), hook.RedisKey, values...).Err()
+	})
+}
+
+func (hook *RedisHook) deliverList(entry *logrus.Entry) error {
+	js, err := hook.formatEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	return hook.sendWithRetry(entry, func() error {
+		return hook.RedisClient.RPush(context.Background(), hook.RedisKey, js).Err()
+	})
+}
+
+func (hook *RedisHook) deliverStream(entry *logrus.Entry) error {
+	values := make(map[string]interface{}, len(entry.Data)+3)
+	for key, value := range entry.Data {
+		values[key] = value
 	}
+	values["message"] = entry.Message
+	values["level"] = entry.Level.String()
+	values["@timestamp"] = entry.Time.UTC().Format(time.RFC3339Nano)
 
-	js, err := json.Marshal(msg)
+	args := &redis.XAddArgs{
+		Stream: hook.RedisKey,
+		MaxLen: hook.MaxLen,
+		Approx: hook.Approximate,
+		Values: values,
+	}
+
+	return hook.sendWithRetry(entry, func() error {
+		return hook.RedisClient.XAdd(context.Background(), args).Err()
+	})
+}
+
+func (hook *RedisHook) deliverPubSub(entry *logrus.Entry) error {
+	js, err := hook.formatEntry(entry)
 	if err != nil {
-		return fmt.Errorf("error creating message for REDIS: %s", err)
+		return err
 	}
 
-	conn := hook.RedisPool.Get()
-	defer conn.Close()
+	return hook.sendWithRetry(entry, func() error {
+		return hook.RedisClient.Publish(context.Background(), hook.RedisKey, js).Err()
+	})
+}
 
-	_, err = conn.Do("RPUSH", hook.RedisKey, js)
+// formatEntry runs the configured Formatter and, on failure, reports it
+// through OnError like a delivery failure, so an entry that can't be
+// formatted isn't silently dropped in async mode. The failure also counts
+// toward SpilloverThreshold, but the entry itself is not written to the
+// spillover file, since spillEntry formats with the same Formatter.
+func (hook *RedisHook) formatEntry(entry *logrus.Entry) ([]byte, error) {
+	js, err := hook.Formatter.Format(entry)
 	if err != nil {
-		return fmt.Errorf("error sending message to REDIS: %s", err)
+		hook.reportFailure(err, []*logrus.Entry{entry})
+		return nil, err
 	}
-	return nil
+	return js, nil
+}
+
+// sendWithRetry runs send, retrying up to MaxRetries times with exponential
+// backoff and jitter. If every attempt fails, it reports the failure via
+// OnError and, once SpilloverThreshold consecutive failures have been
+// reached, appends the entry to SpilloverPath.
+func (hook *RedisHook) sendWithRetry(entry *logrus.Entry, send func() error) error {
+	return hook.sendBatchWithRetry([]*logrus.Entry{entry}, send)
+}
+
+// sendBatchWithRetry is sendWithRetry for a batch of entries delivered by a
+// single send call; on failure, every entry in the batch is reported to
+// OnError and spillover individually.
+func (hook *RedisHook) sendBatchWithRetry(entries []*logrus.Entry, send func() error) error {
+	var err error
+	for attempt := 0; attempt <= hook.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoffWithJitter(hook.RetryBaseDelay, attempt))
+		}
+		if err = send(); err == nil {
+			hook.mu.Lock()
+			hook.consecutiveFailures = 0
+			hook.mu.Unlock()
+			return nil
+		}
+	}
+
+	err = fmt.Errorf("error sending message to REDIS: %s", err)
+	hook.reportFailure(err, entries)
+	return err
+}
+
+// reportFailure notifies OnError and, once SpilloverThreshold consecutive
+// failures have been observed, appends each entry to SpilloverPath.
+func (hook *RedisHook) reportFailure(err error, entries []*logrus.Entry) {
+	hook.mu.Lock()
+	hook.consecutiveFailures++
+	spill := hook.SpilloverPath != "" && hook.consecutiveFailures >= hook.SpilloverThreshold
+	hook.mu.Unlock()
+
+	for _, entry := range entries {
+		if hook.OnError != nil {
+			hook.OnError(err, entry)
+		}
+		if spill {
+			hook.spillEntry(entry)
+		}
+	}
+}
+
+// spillEntry appends entry to SpilloverPath as a JSONL line, opening the
+// file on first use and keeping it open for subsequent writes.
+func (hook *RedisHook) spillEntry(entry *logrus.Entry) {
+	js, err := hook.Formatter.Format(entry)
+	if err != nil {
+		return
+	}
+
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+
+	if hook.spilloverFile == nil {
+		f, err := os.OpenFile(hook.SpilloverPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return
+		}
+		hook.spilloverFile = f
+	}
+
+	// RawFormatter output already ends in a newline; don't add a blank line.
+	if len(js) == 0 || js[len(js)-1] != '\n' {
+		js = append(js, '\n')
+	}
+	hook.spilloverFile.Write(js)
+}
+
+// maxBackoff caps the doubled delay in backoffWithJitter before jitter is
+// added, so a large MaxRetries can't overflow the doubling into a negative
+// duration. With jitter, the returned delay can reach 1.5 * maxBackoff.
+const maxBackoff = 30 * time.Second
+
+// backoffWithJitter returns a delay for the given retry attempt (1-indexed),
+// doubling base on each attempt up to maxBackoff and adding up to 50%
+// jitter to avoid retry storms against a recovering Redis instance.
+func backoffWithJitter(base time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
 }
 
 func createV0Message(entry *logrus.Entry) LogstashMessageV0 {
@@ -192,7 +694,7 @@ func createV1Message(entry *logrus.Entry) LogstashMessageV1 {
 func logEntryToStringMap(entry *logrus.Entry) map[string]string {
 	m := make(map[string]string)
 
-	if (len(entry.Data) > 0) {
+	if len(entry.Data) > 0 {
 		for key, value := range entry.Data {
 			if str, ok := value.(string); ok {
 				m[key] = str
@@ -202,34 +704,7 @@ func logEntryToStringMap(entry *logrus.Entry) map[string]string {
 		}
 	}
 
-	return m;
-}
-
-func newRedisConnectionPool(server string, port int) *redis.Pool {
-	hostPort := fmt.Sprintf("%s:%d", server, port)
-	return &redis.Pool{
-		MaxIdle:     3,
-		IdleTimeout: 240 * time.Second,
-		Dial: func() (redis.Conn, error) {
-			c, err := redis.Dial("tcp", hostPort)
-			if err != nil {
-				return nil, err
-			}
-
-			// if password != "" {
-			// 	if _, err := c.Do("AUTH", password); err != nil {
-			// 		c.Close()
-			// 		return nil, err
-			// 	}
-			// }
-
-			return c, err
-		},
-		TestOnBorrow: func(c redis.Conn, t time.Time) error {
-			_, err := c.Do("PING")
-			return err
-		},
-	}
+	return m
 }
 
 func reportHostname() string {
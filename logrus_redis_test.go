@@ -0,0 +1,183 @@
+package logredis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeRedisClient is a minimal redisClient for exercising RedisHook without a
+// real Redis server.
+type fakeRedisClient struct {
+	mu sync.Mutex
+
+	rpushDelay  time.Duration
+	rpushErr    error
+	rpushValues []interface{}
+
+	closed   bool
+	closeErr error
+}
+
+func (f *fakeRedisClient) RPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	f.mu.Lock()
+	delay := f.rpushDelay
+	err := f.rpushErr
+	f.rpushValues = append(f.rpushValues, values...)
+	f.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	cmd := redis.NewIntCmd(ctx)
+	if err != nil {
+		cmd.SetErr(err)
+	}
+	return cmd
+}
+
+func (f *fakeRedisClient) XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd {
+	return redis.NewStringCmd(ctx)
+}
+
+func (f *fakeRedisClient) Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd {
+	return redis.NewIntCmd(ctx)
+}
+
+func (f *fakeRedisClient) Ping(ctx context.Context) *redis.StatusCmd {
+	cmd := redis.NewStatusCmd(ctx)
+	cmd.SetVal("PONG")
+	return cmd
+}
+
+func (f *fakeRedisClient) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return f.closeErr
+}
+
+func (f *fakeRedisClient) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+func (f *fakeRedisClient) rpushValueCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.rpushValues)
+}
+
+// failingFormatter fails to format any entry whose message is in fail.
+type failingFormatter struct {
+	fail map[string]bool
+}
+
+func (f failingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if f.fail[entry.Message] {
+		return nil, fmt.Errorf("error creating message for REDIS: boom: %s", entry.Message)
+	}
+	return []byte(entry.Message), nil
+}
+
+func newAsyncTestHook(client redisClient) *RedisHook {
+	hook := &RedisHook{
+		RedisClient:    client,
+		RedisKey:       "test",
+		Formatter:      LogstashV0Formatter{},
+		Async:          true,
+		BatchSize:      1,
+		FlushInterval:  10 * time.Millisecond,
+		RetryBaseDelay: time.Millisecond,
+	}
+	hook.EntryQueue = make(chan *logrus.Entry, 10)
+	hook.Quit = make(chan int)
+	hook.done = make(chan struct{})
+	go hook.asyncProcessing()
+	return hook
+}
+
+func TestCloseSucceedsOnRetryAfterTimeout(t *testing.T) {
+	fake := &fakeRedisClient{rpushDelay: 50 * time.Millisecond}
+	hook := newAsyncTestHook(fake)
+
+	hook.EntryQueue <- &logrus.Entry{Message: "hi", Level: logrus.InfoLevel, Time: time.Now()}
+	time.Sleep(5 * time.Millisecond) // let asyncProcessing pick the entry up
+
+	shortCtx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := hook.Close(shortCtx); err == nil {
+		t.Fatal("expected first Close to time out while the batch is still flushing")
+	}
+
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("expected retried Close to succeed, got %v", err)
+	}
+
+	if !fake.isClosed() {
+		t.Fatal("expected RedisClient.Close to be called once the hook actually shuts down")
+	}
+}
+
+func TestCloseIsIdempotentOnSuccess(t *testing.T) {
+	fake := &fakeRedisClient{}
+	hook := newAsyncTestHook(fake)
+
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error from first Close: %v", err)
+	}
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error from second Close: %v", err)
+	}
+}
+
+func TestProcessBatchReportsFormatErrors(t *testing.T) {
+	fake := &fakeRedisClient{}
+	var onErrorEntries []string
+
+	hook := &RedisHook{
+		RedisClient:        fake,
+		RedisKey:           "test",
+		Formatter:          failingFormatter{fail: map[string]bool{"bad": true}},
+		SpilloverThreshold: 1,
+		OnError: func(err error, entry *logrus.Entry) {
+			onErrorEntries = append(onErrorEntries, entry.Message)
+		},
+	}
+
+	entries := []*logrus.Entry{
+		{Message: "good", Level: logrus.InfoLevel, Time: time.Now()},
+		{Message: "bad", Level: logrus.InfoLevel, Time: time.Now()},
+	}
+
+	if err := hook.processBatch(entries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(onErrorEntries) != 1 || onErrorEntries[0] != "bad" {
+		t.Fatalf("expected OnError to fire once for the unformattable entry, got %v", onErrorEntries)
+	}
+
+	if got := fake.rpushValueCount(); got != 1 {
+		t.Fatalf("expected only the formattable entry to reach RPUSH, got %d values", got)
+	}
+}
+
+func TestBackoffWithJitterDoesNotOverflowOrPanic(t *testing.T) {
+	for attempt := 1; attempt <= 64; attempt++ {
+		d := backoffWithJitter(100*time.Millisecond, attempt)
+		if d <= 0 {
+			t.Fatalf("attempt %d: expected a positive delay, got %v", attempt, d)
+		}
+		if d > maxBackoff+maxBackoff/2+1 {
+			t.Fatalf("attempt %d: expected delay to stay near maxBackoff, got %v", attempt, d)
+		}
+	}
+}